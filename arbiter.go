@@ -1,22 +1,57 @@
 package main
 
 import (
+	"bytes"
+	"crypto/tls"
 	"encoding/json"
 	"flag"
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/solvip/arbiter/pool"
+	"github.com/solvip/arbiter/scram"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 	"io"
-	"log"
 	"net"
 	"net/http"
 	_ "net/http/pprof"
+	"os"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 )
 
 type connectionHandler func(net.Conn)
 
+// cancelKey identifies the backend a CancelRequest should be forwarded to;
+// it's the (pid, secret) pair the backend handed us in its BackendKeyData.
+type cancelKey struct {
+	pid    int32
+	secret int32
+}
+
 type server struct {
-	pool *pool.Pool
+	pool      *pool.Pool
+	logger    *zap.SugaredLogger
+	metrics   *promMetrics
+	tlsConfig *TLSConfig
+
+	// readonlyUsers routes these database users to a follower even when
+	// they connect through the primary listener.
+	readonlyUsers map[string]bool
+
+	cancelMu   sync.Mutex
+	cancelKeys map[cancelKey]string
+
+	// retryMaxAttempts is how many times a buffered transaction is replayed
+	// after a serialization failure or deadlock before the error is given
+	// to the client.  0 disables automatic retry.
+	retryMaxAttempts int
+
+	// retryMaxBufferBytes caps how much of a transaction's frontend
+	// messages are buffered for a possible replay.
+	retryMaxBufferBytes int
 
 	// Bytes transferred
 	transferred AtomicInt
@@ -39,49 +74,126 @@ func main() {
 	httpAddr := flag.String("p", "127.0.0.1:6060", "Enable the HTTP status interface")
 	cfgPath := flag.String("f", "/etc/arbiter/config.ini",
 		"The path to the arbiter configuration file")
+	logFormat := flag.String("log-format", "console", "Log output format: json or console")
+	logLevel := flag.String("log-level", "info", "Minimum log level to emit: debug, info, warn, error")
 	flag.Parse()
 
+	zapLogger, err := newZapLogger(*logFormat, *logLevel)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not configure logger: %s\n", err)
+		os.Exit(1)
+	}
+	defer zapLogger.Sync()
+
+	logger := zapLogger.Sugar()
+
 	c, err := ConfigFromFile(*cfgPath)
 	if err != nil {
-		log.Fatalf("Could not load configuration file: %s", err)
+		logger.Fatalw("could not load configuration file", "path", *cfgPath, "err", err)
+	}
+
+	tlsConfig, err := buildTLSConfig(c)
+	if err != nil {
+		logger.Fatalw("could not configure TLS", "err", err)
 	}
 
+	readonlyUsers := make(map[string]bool, len(c.Main.ReadonlyUsers))
+	for _, user := range c.Main.ReadonlyUsers {
+		readonlyUsers[user] = true
+	}
+
+	metrics := newPromMetrics()
+
 	s := &server{
-		pool: pool.New(),
+		pool:                pool.New(pool.NewLogger(zapLogger), metrics),
+		logger:              logger,
+		metrics:             metrics,
+		tlsConfig:           tlsConfig,
+		readonlyUsers:       readonlyUsers,
+		cancelKeys:          make(map[cancelKey]string),
+		retryMaxAttempts:    c.Retry.MaxAttempts,
+		retryMaxBufferBytes: c.Retry.MaxBufferBytes,
 	}
 
 	for _, addr := range c.Main.Backends {
-		s.pool.Put(pool.NewPostgresBackend(addr, c.Health.Username, c.Health.Password, c.Health.Database))
+		s.pool.Put(pool.NewPostgresBackend(addr, c.Health.Username, c.Health.Password, c.Health.Database, pool.NewLogger(zapLogger), metrics))
 	}
 
 	go func() {
-		log.Printf("Starting HTTP server; listening on %s", *httpAddr)
+		logger.Infow("starting HTTP server", "addr", *httpAddr)
 		http.HandleFunc("/stats", s.handleStats)
-		log.Fatal(http.ListenAndServe(*httpAddr, nil))
+		http.Handle("/metrics", promhttp.Handler())
+		logger.Fatalw("http server exited", "err", http.ListenAndServe(*httpAddr, nil))
 	}()
 
 	go func() {
-		log.Printf("Starting follower listener; listening on %s", c.Main.Follower)
+		logger.Infow("starting follower listener", "addr", c.Main.Follower)
 		if err := s.startListener(c.Main.Follower, pool.READ_ONLY); err != nil {
-			log.Fatalf("Could not start Arbiter: %s", err)
+			logger.Fatalw("could not start arbiter", "listener", "follower", "err", err)
 		}
 	}()
 
-	log.Printf("Starting primary listener; listening on %s", c.Main.Primary)
+	logger.Infow("starting primary listener", "addr", c.Main.Primary)
 	if err := s.startListener(c.Main.Primary, pool.READ_WRITE); err != nil {
-		log.Fatalf("Could not start Arbiter: %s", err)
+		logger.Fatalw("could not start arbiter", "listener", "primary", "err", err)
 	}
 
 	return
 }
 
+// newZapLogger builds a *zap.Logger from the --log-format and --log-level flags.
+func newZapLogger(format, level string) (*zap.Logger, error) {
+	var lvl zapcore.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, err
+	}
+
+	var cfg zap.Config
+	switch format {
+	case "json":
+		cfg = zap.NewProductionConfig()
+	case "console":
+		cfg = zap.NewDevelopmentConfig()
+	default:
+		return nil, fmt.Errorf("unknown log format %q; want json or console", format)
+	}
+	cfg.Level = zap.NewAtomicLevelAt(lvl)
+
+	return cfg.Build()
+}
+
 func (s *server) handleStats(w http.ResponseWriter, req *http.Request) {
+	type backendStats struct {
+		Addr          string  `json:"addr"`
+		State         int     `json:"state"`
+		LatencyMs     float64 `json:"latency_ms"`
+		NextProbeUnix int64   `json:"next_probe_unix,omitempty"`
+	}
+
+	backends := s.pool.Status()
+	backendStatsList := make([]backendStats, len(backends))
+	for i, b := range backends {
+		var nextProbeUnix int64
+		if !b.NextProbe.IsZero() {
+			nextProbeUnix = b.NextProbe.Unix()
+		}
+
+		backendStatsList[i] = backendStats{
+			Addr:          b.Addr,
+			State:         int(b.State),
+			LatencyMs:     float64(b.Latency) / float64(time.Millisecond),
+			NextProbeUnix: nextProbeUnix,
+		}
+	}
+
 	curStats := struct {
-		TransferredBytes    int64 `json:"transferred_bytes"`
-		NumberOfConnections int64 `json:"connections"`
+		TransferredBytes    int64          `json:"transferred_bytes"`
+		NumberOfConnections int64          `json:"connections"`
+		Backends            []backendStats `json:"backends"`
 	}{
 		s.transferred.Get(),
 		s.nconns.Get(),
+		backendStatsList,
 	}
 
 	b, err := json.MarshalIndent(curStats, "", "  ")
@@ -101,18 +213,40 @@ func (s *server) startListener(addr string, state pool.State) error {
 	for {
 		clientConn, err := ln.Accept()
 		if err != nil {
-			log.Printf("Error accepting client: %s", err)
+			s.logger.Warnw("error accepting client", "listen_addr", addr, "err", err)
 			continue
 		}
 
 		go func() {
-			defer clientConn.Close()
+			conn := net.Conn(clientConn)
+			defer func() { conn.Close() }()
+
+			startMsg, err := s.acceptStartup(&conn)
+			if err != nil {
+				s.logger.Warnw("error reading startup message", "listen_addr", addr, "err", err)
+				return
+			}
+
+			if cancel, ok := startMsg.(*CancelRequest); ok {
+				s.handleCancel(cancel)
+				return
+			}
+
+			startup, ok := startMsg.(*Startup)
+			if !ok {
+				s.logger.Warnw("unexpected startup message", "listen_addr", addr)
+				return
+			}
 
 			s.nconns.Add(1)
-			var err error
-			var backend pool.Backend
+			s.metrics.connectionsActive.Inc()
+			defer func() {
+				s.nconns.Add(-1)
+				s.metrics.connectionsActive.Dec()
+			}()
 
-			switch state {
+			var backend pool.Backend
+			switch s.routeState(state, startup.User()) {
 			case pool.READ_ONLY:
 				backend, err = s.pool.GetForRead()
 			case pool.READ_WRITE:
@@ -122,74 +256,747 @@ func (s *server) startListener(addr string, state pool.State) error {
 			}
 
 			if err != nil {
-				log.Printf("Couldn't retrieve a backend: %s", err)
+				s.logger.Warnw("couldn't retrieve a backend", "listen_addr", addr, "err", err)
 				return
 			}
 
 			backendConn, err := backend.Connect(5 * time.Second)
 			if err != nil {
-				log.Printf("Couldn't connect to backend: %s", err)
+				s.logger.Warnw("couldn't connect to backend", "backend_addr", backend.Addr(), "err", err)
 				return
 			}
 			defer backendConn.Close()
 
-			err = s.proxy(clientConn, backendConn)
+			// This connection is about to carry the real startup and
+			// authentication exchange; it's no longer eligible to be
+			// recycled into the backend's warm pool.
+			backendConn.Consume()
+
+			var backendRW io.ReadWriter = backendConn
+			if s.tlsConfig != nil && s.tlsConfig.Backend != nil {
+				tlsConn, err := negotiateBackendTLS(backendConn, s.tlsConfig.Backend)
+				if err != nil {
+					s.logger.Warnw("couldn't negotiate TLS with backend", "backend_addr", backend.Addr(), "err", err)
+					backend.Fail()
+					return
+				}
+				backendRW = tlsConn
+			}
+
+			if err := startup.EncodeTo(backendRW); err != nil {
+				s.logger.Warnw("couldn't forward startup message to backend", "backend_addr", backend.Addr(), "err", err)
+				backend.Fail()
+				return
+			}
+
+			pid, secret, err := s.relayAuthPhase(conn, backendRW, startup)
+			if err != nil {
+				s.logger.Warnw("error authenticating with backend", "backend_addr", backend.Addr(), "err", err)
+				backend.Fail()
+				return
+			}
+
+			key := cancelKey{pid, secret}
+			s.cancelMu.Lock()
+			s.cancelKeys[key] = backend.Addr()
+			s.cancelMu.Unlock()
+			defer func() {
+				s.cancelMu.Lock()
+				delete(s.cancelKeys, key)
+				s.cancelMu.Unlock()
+			}()
+
+			err = s.proxy(conn, backendRW)
 			if err != io.EOF {
-				log.Printf("Error writing to or reading from backend: %v", err)
+				s.logger.Warnw("error proxying to backend", "backend_addr", backend.Addr(), "err", err)
 				backend.Fail()
 			}
-			s.nconns.Add(-1)
 		}()
 	}
 }
 
-// Proxy frontend <-> backend.
+// acceptStartup reads startup messages off *conn, answering SSLRequest
+// according to s.tlsConfig.Client: 'N' (and keep reading plaintext) when TLS
+// termination isn't configured, or 'S' followed by a TLS handshake -
+// upgrading *conn in place - when it is. It returns the Startup or
+// CancelRequest that ends the loop.
+func (s *server) acceptStartup(conn *net.Conn) (StartMessage, error) {
+	for {
+		msg, err := readStartMessage(*conn)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := msg.(*SSLRequest); !ok {
+			return msg, nil
+		}
+
+		if s.tlsConfig == nil || s.tlsConfig.Client == nil {
+			if _, err := (*conn).Write([]byte{'N'}); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if _, err := (*conn).Write([]byte{'S'}); err != nil {
+			return nil, err
+		}
+
+		tlsConn := tls.Server(*conn, s.tlsConfig.Client)
+		if err := tlsConn.Handshake(); err != nil {
+			return nil, err
+		}
+		*conn = tlsConn
+	}
+}
+
+// routeState returns defaultState, unless user is configured as a read-only
+// user, in which case a write listener is downgraded to READ_ONLY.
+func (s *server) routeState(defaultState pool.State, user string) pool.State {
+	if defaultState == pool.READ_WRITE && s.readonlyUsers[user] {
+		return pool.READ_ONLY
+	}
+
+	return defaultState
+}
+
+// relayAuthPhase forwards the backend's authentication exchange to the
+// client message-by-message, relaying the client's replies back to the
+// backend raw, until the backend reports ReadyForQuery.  It returns the
+// backend's BackendKeyData so cancel requests can later be routed to it.
+//
+// A backend asking for SASL is handled specially: rather than exposing the
+// SCRAM exchange to the client, authenticateSCRAM intercepts it and asks the
+// client for a plain password instead, so clients with no SCRAM support of
+// their own can still reach a backend that requires it.
+func (s *server) relayAuthPhase(client io.ReadWriter, backend io.ReadWriter, startup *Startup) (pid, secret int32, err error) {
+	for {
+		var msg Message
+		msg, err = readBackendMessage(backend)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		switch m := msg.(type) {
+		case *NegotiateProtocolVersion:
+			// The arbiter forwards Startup to the backend exactly as the
+			// client sent it, so a negotiation down to an older minor
+			// version or a rejected "_pq_." option is relayed transparently
+			// and settled directly between client and backend.
+			s.logger.Debugw("backend negotiated protocol version",
+				"backend_minor_version", m.MinorVersion, "unrecognized_options", m.UnrecognizedOptions)
+
+			if err = msg.EncodeTo(client); err != nil {
+				return 0, 0, err
+			}
+
+		case *AuthenticationRequest:
+			if m.Type == SASL {
+				if err = s.authenticateSCRAM(client, backend, startup.User(), m.Mechanisms()); err != nil {
+					return 0, 0, err
+				}
+				continue
+			}
+
+			if err = msg.EncodeTo(client); err != nil {
+				return 0, 0, err
+			}
+			if m.Type != OK {
+				if err = copyRawMessage(backend, client); err != nil {
+					return 0, 0, err
+				}
+			}
+
+		case *BackendKeyData:
+			pid, secret = m.pid, m.secret
+			if err = msg.EncodeTo(client); err != nil {
+				return 0, 0, err
+			}
+
+		case *ReadyForQuery:
+			if err = msg.EncodeTo(client); err != nil {
+				return 0, 0, err
+			}
+			return pid, secret, nil
+
+		case *ErrorResponse:
+			return 0, 0, fmt.Errorf("backend rejected authentication: %s", m.Message())
+
+		default:
+			if err = msg.EncodeTo(client); err != nil {
+				return 0, 0, err
+			}
+		}
+	}
+}
+
+// authenticateSCRAM completes a SCRAM-SHA-256(-PLUS) exchange with backend
+// on behalf of user, without ever exposing SASL to the client: it prompts
+// the client for a cleartext password and uses that to drive the real
+// exchange.
+//
+// Channel binding is used whenever the backend offers SCRAM-SHA-256-PLUS
+// and the connection to backend is TLS-terminated: tlsChannelBindingHash
+// gives it the tls-server-end-point hash of the backend's certificate. A
+// backend that offers only the -PLUS mechanism (requiring channel binding)
+// cannot be authenticated against over a plaintext backend connection.
+//
+// Note that the client is always prompted for a cleartext password here,
+// even over a plaintext client connection - the arbiter is the one SCRAM
+// peer, not the client, so there's no client-side SCRAM exchange to relay.
+// Operators who need the password to never cross the wire in the clear
+// must terminate TLS on the client listener (TLSConfig.Client); arbiter
+// does not refuse a plaintext client leg on its own.
+func (s *server) authenticateSCRAM(client, backend io.ReadWriter, user string, mechanisms []string) error {
+	var plain, plus bool
+	for _, m := range mechanisms {
+		switch m {
+		case scram.SHA256:
+			plain = true
+		case scram.SHA256Plus:
+			plus = true
+		}
+	}
+	if !plain && !plus {
+		return fmt.Errorf("backend offered no supported SASL mechanism: %v", mechanisms)
+	}
+
+	cbind := tlsChannelBindingHash(backend)
+	if plus && !plain && cbind == nil {
+		return fmt.Errorf("backend requires SCRAM-SHA-256-PLUS, but the backend connection isn't TLS-terminated")
+	}
+
+	prompt := &AuthenticationRequest{Type: CleartextPassword}
+	if err := prompt.EncodeTo(client); err != nil {
+		return err
+	}
+
+	var pw PasswordMessage
+	if err := pw.DecodeFrom(client); err != nil {
+		return err
+	}
+
+	var sc *scram.Client
+	if plus && cbind != nil {
+		sc = scram.NewClientWithChannelBinding(user, string(pw.Password()), cbind)
+	} else {
+		sc = scram.NewClient(user, string(pw.Password()))
+	}
+
+	initial := &SASLInitialResponse{Mechanism: sc.Mechanism(), Data: sc.FirstMessage()}
+	if err := initial.EncodeTo(backend); err != nil {
+		return err
+	}
+
+	continueMsg, err := readBackendMessage(backend)
+	if err != nil {
+		return err
+	}
+	cont, ok := continueMsg.(*AuthenticationRequest)
+	if !ok || cont.Type != SASLContinue {
+		return ErrProtocolViolation
+	}
+
+	final, err := sc.FinalMessage(cont.Data())
+	if err != nil {
+		return err
+	}
+
+	resp := SASLResponse(final)
+	if err := resp.EncodeTo(backend); err != nil {
+		return err
+	}
+
+	finalMsg, err := readBackendMessage(backend)
+	if err != nil {
+		return err
+	}
+	fin, ok := finalMsg.(*AuthenticationRequest)
+	if !ok || fin.Type != SASLFinal {
+		return ErrProtocolViolation
+	}
+
+	if err := sc.VerifyServerFinalMessage(fin.Data()); err != nil {
+		return err
+	}
+
+	okMsg, err := readBackendMessage(backend)
+	if err != nil {
+		return err
+	}
+	okReq, ok := okMsg.(*AuthenticationRequest)
+	if !ok || okReq.Type != OK {
+		return ErrProtocolViolation
+	}
+
+	return (&AuthenticationRequest{Type: OK}).EncodeTo(client)
+}
+
+// handleCancel forwards a CancelRequest to whichever backend we last
+// recorded as owning its (pid, secret) pair.  Per the protocol, the arbiter
+// doesn't reply to the client at all; it simply opens a fresh connection to
+// the backend, performs the same SSLRequest dance the original proxied
+// connection would have, forwards the request and closes it.
+func (s *server) handleCancel(req *CancelRequest) {
+	s.cancelMu.Lock()
+	addr, ok := s.cancelKeys[cancelKey{req.pid, req.secret}]
+	s.cancelMu.Unlock()
+
+	if !ok {
+		s.logger.Warnw("cancel request for unknown backend key")
+		return
+	}
+
+	conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	if err != nil {
+		s.logger.Warnw("couldn't dial backend to forward cancel request", "backend_addr", addr, "err", err)
+		return
+	}
+	defer conn.Close()
+
+	var backendConn net.Conn = conn
+	if s.tlsConfig != nil && s.tlsConfig.Backend != nil {
+		backendConn, err = negotiateBackendTLS(conn, s.tlsConfig.Backend)
+		if err != nil {
+			s.logger.Warnw("couldn't negotiate TLS with backend to forward cancel request", "backend_addr", addr, "err", err)
+			return
+		}
+	}
+
+	if err := req.EncodeTo(backendConn); err != nil {
+		s.logger.Warnw("couldn't forward cancel request", "backend_addr", addr, "err", err)
+	}
+}
+
+// SQLSTATE codes the arbiter will transparently retry a buffered
+// transaction for: serialization_failure and deadlock_detected.
+const (
+	sqlStateSerializationFailure = "40001"
+	sqlStateDeadlockDetected     = "40P01"
+)
+
+// retryBackoffInitial, retryBackoffFactor and retryBackoffMax govern the
+// delay before replaying a transaction: it starts at retryBackoffInitial and
+// doubles with every attempt, up to retryBackoffMax.
+const (
+	retryBackoffInitial = 50 * time.Millisecond
+	retryBackoffFactor  = 2
+	retryBackoffMax     = 2 * time.Second
+)
+
+// retryBackoff returns how long to wait before replaying a transaction for
+// the given attempt (1-indexed).
+func retryBackoff(attempt int) time.Duration {
+	d := retryBackoffInitial
+	for i := 1; i < attempt; i++ {
+		d *= retryBackoffFactor
+		if d >= retryBackoffMax {
+			return retryBackoffMax
+		}
+	}
+
+	return d
+}
+
+// txBuffer accumulates the frontend messages sent since the client's
+// transaction began - either an explicit BEGIN, or an implicit,
+// single-statement transaction started right after an idle ReadyForQuery -
+// so that a serialization failure or deadlock can be retried by rolling
+// back and replaying them against the same backend.
+//
+// It's reset by the backend->frontend relay goroutine whenever the backend
+// reports ReadyForQuery with status 'I'; the frontend->backend goroutine
+// only ever appends to it.  Both goroutines may reach it concurrently, so
+// every access goes through mu.
+//
+// retryTransaction's rollback-and-replay is only correct while the client is
+// synchronized: blocked waiting for the reply to its last Query/Sync before
+// sending the next one, so the frontend->backend goroutine is idle for the
+// whole of the retry.  A pipelining client (one that fires its next message
+// before the previous one's ReadyForQuery comes back) could have that next
+// message already read, buffered and written to the backend while the retry
+// is in flight, interleaving it with the ROLLBACK/replay on the wire.
+// outstandingSyncs tracks this: noteSyncSent marks the buffer unsafe the
+// moment it sees a second Query/Sync sent before the first is acknowledged.
+type txBuffer struct {
+	mu sync.Mutex
+
+	msgs [][]byte
+	size int
+
+	unsafe       bool // CopyData/CopyDone, a savepoint statement, or pipelining seen: never retry
+	responseSent bool // some backend response besides the closing ReadyForQuery already reached the client
+	overflowed   bool // buffered messages exceeded maxBytes
+	retries      int
+
+	outstandingSyncs int // Query/Sync messages sent since the last ReadyForQuery
+}
+
+// reset clears the buffer and all per-transaction flags, starting a fresh
+// retry window.
+func (b *txBuffer) reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.msgs = nil
+	b.size = 0
+	b.unsafe = false
+	b.responseSent = false
+	b.overflowed = false
+	b.retries = 0
+	b.outstandingSyncs = 0
+}
+
+// noteSyncSent records that a Query or Sync message was just forwarded to
+// the backend. A synchronized client never has more than one outstanding at
+// a time; if this one finds another already outstanding, the client is
+// pipelining and the buffer is marked unsafe - see the txBuffer doc comment.
+func (b *txBuffer) noteSyncSent() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.outstandingSyncs++
+	if b.outstandingSyncs > 1 {
+		b.unsafe = true
+	}
+}
+
+// noteReadyForQuery records that a ReadyForQuery was received from the
+// backend, closing out the Query/Sync cycle noteSyncSent opened.
+func (b *txBuffer) noteReadyForQuery() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.outstandingSyncs > 0 {
+		b.outstandingSyncs--
+	}
+}
+
+// append records a frontend message that was just forwarded to the backend.
+// unsafe marks the message as making the transaction unretryable regardless
+// of anything else (a COPY payload, or a savepoint the server may already
+// have acknowledged). Once the buffer's total size would exceed maxBytes,
+// it's dropped and marked overflowed rather than grown further.
+func (b *txBuffer) append(raw []byte, maxBytes int, unsafe bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if unsafe {
+		b.unsafe = true
+	}
+
+	if b.overflowed {
+		return
+	}
+
+	if b.size+len(raw) > maxBytes {
+		b.overflowed = true
+		b.msgs = nil
+		b.size = 0
+		return
+	}
+
+	b.msgs = append(b.msgs, append([]byte(nil), raw...))
+	b.size += len(raw)
+}
+
+// markResponseSent records that some backend response other than the
+// closing ReadyForQuery was forwarded to the client in the current window,
+// which rules out a later retry: retryTransaction replays the whole
+// buffered window from the start, and the client must never see any of its
+// output a second time - not just RowDescription/DataRow, but also e.g. a
+// ParseComplete/BindComplete that preceded a later Execute's error, or an
+// earlier statement's CommandComplete in a multi-statement simple Query.
+func (b *txBuffer) markResponseSent() {
+	b.mu.Lock()
+	b.responseSent = true
+	b.mu.Unlock()
+}
+
+// beginRetry reports whether the current window may be retried: it's not
+// unsafe, no response has already reached the client, it didn't overflow,
+// and maxAttempts hasn't been used up.  On success, it returns the buffered
+// messages slice itself (not a copy) and increments the attempt counter.
+func (b *txBuffer) beginRetry(maxAttempts int) (msgs [][]byte, attempt int, ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.unsafe || b.responseSent || b.overflowed || len(b.msgs) == 0 || b.retries >= maxAttempts {
+		return nil, 0, false
+	}
+
+	b.retries++
+	return b.msgs, b.retries, true
+}
+
+// isUnsafeToRetry reports whether msg makes its transaction unsafe to replay
+// from the start: SAVEPOINT, RELEASE (SAVEPOINT) and ROLLBACK TO all
+// establish or consume a server-side checkpoint the arbiter has no way to
+// resume from once the server has acknowledged it.
+func isUnsafeToRetry(msg Message) bool {
+	var stmt string
+	switch m := msg.(type) {
+	case *Query:
+		stmt = m.String
+	case *Parse:
+		stmt = m.Query
+	default:
+		return false
+	}
+
+	stmt = strings.ToUpper(strings.TrimSpace(stmt))
+	return strings.HasPrefix(stmt, "SAVEPOINT") ||
+		strings.HasPrefix(stmt, "RELEASE") ||
+		strings.HasPrefix(stmt, "ROLLBACK TO")
+}
+
+// drainUntilReadyForQuery reads and discards backend messages up to and
+// including the next ReadyForQuery, without forwarding any of them to the
+// client.  It's used to consume responses the client must never see: the
+// tail of a failed attempt, and the ROLLBACK's own CommandComplete.
+func drainUntilReadyForQuery(backend io.ReadWriter) error {
+	for {
+		msg, err := readBackendMessage(backend)
+		if err != nil {
+			return err
+		}
+		if _, ok := msg.(*ReadyForQuery); ok {
+			return nil
+		}
+	}
+}
+
+// syncPoints counts the messages in msgs that make the backend emit a
+// ReadyForQuery once processed: a simple Query, or an extended-protocol
+// Sync.  The caller uses it to work out how many ReadyForQuery cycles the
+// replay in retryTransaction will produce.
+func syncPoints(msgs [][]byte) int {
+	n := 0
+	for _, m := range msgs {
+		if len(m) == 0 {
+			continue
+		}
+		switch m[0] {
+		case 'Q', 'S':
+			n++
+		}
+	}
+	return n
+}
+
+// retryTransaction rolls back and replays msgs against backend, suppressing
+// the triggering ErrorResponse from the client entirely.  It reports the
+// number of ReadyForQuery cycles the caller must swallow from the replay
+// before resuming normal forwarding - every msgs entry but the last one that
+// originally failed was already acknowledged to the client the first time
+// around, so only the last cycle's response may reach them.  ok reports
+// whether the replay was sent; the caller must forward the original error
+// to the client if it returns false.
+func (s *server) retryTransaction(backend io.ReadWriter, write func([]byte) error, msgs [][]byte, attempt int, sqlstate string) (swallow int, ok bool) {
+	// Drain the ReadyForQuery that follows the ErrorResponse we just read:
+	// it hasn't been consumed yet, and the client must not see it either.
+	if err := drainUntilReadyForQuery(backend); err != nil {
+		return 0, false
+	}
+
+	var raw bytes.Buffer
+	if err := (&Query{String: "ROLLBACK"}).EncodeTo(&raw); err != nil {
+		return 0, false
+	}
+	if err := write(raw.Bytes()); err != nil {
+		return 0, false
+	}
+
+	// Drain the backend's response to our ROLLBACK without forwarding any
+	// of it to the client, who never saw the failed attempt succeed.
+	if err := drainUntilReadyForQuery(backend); err != nil {
+		return 0, false
+	}
+
+	time.Sleep(retryBackoff(attempt))
+
+	for _, m := range msgs {
+		if err := write(m); err != nil {
+			return 0, false
+		}
+	}
+
+	s.metrics.transactionRetried(sqlstate)
+
+	swallow = syncPoints(msgs) - 1
+	if swallow < 0 {
+		swallow = 0
+	}
+	return swallow, true
+}
+
+// Proxy frontend <-> backend, message by message.  A serialization failure
+// or deadlock reported by the backend is transparently retried by rolling
+// back and replaying the client's current transaction, provided it's safe
+// to do so - see txBuffer.
 // err will be the first error encountered reading from- or writing to backend.
 func (s *server) proxy(frontend, backend io.ReadWriter) (err error) {
-	errch := make(chan error)
+	errch := make(chan error, 2)
+
+	buf := &txBuffer{}
+
+	// backendWriteMu serializes writes to backend: the frontend->backend
+	// relay writes client messages as they arrive, while the
+	// backend->frontend relay writes the ROLLBACK and replayed messages a
+	// retry needs.  Neither may interleave with the other on the wire.
+	var backendWriteMu sync.Mutex
+	writeToBackend := func(raw []byte) error {
+		backendWriteMu.Lock()
+		defer backendWriteMu.Unlock()
+
+		n, err := backend.Write(raw)
+		s.transferred.Add(int64(n))
+		s.metrics.bytesTransferred.WithLabelValues("in").Add(float64(n))
+		return err
+	}
+
+	// writeMsgToBackend is the coalesced-write fast path for forwarding a
+	// frontend message verbatim: it skips the intermediate bytes.Buffer
+	// writeToBackend requires, the same way the backend->frontend relay
+	// below writes straight to frontendWriter. It's only used when no
+	// buffered copy of raw bytes is needed, i.e. retry is disabled.
+	backendWriter := NewMessageWriter(backend)
+	writeMsgToBackend := func(msg Message) (int, error) {
+		backendWriteMu.Lock()
+		defer backendWriteMu.Unlock()
+
+		if fe, ok := msg.(FastEncoder); ok {
+			return fe.EncodeToWriter(backendWriter)
+		}
+
+		var raw bytes.Buffer
+		if err := msg.EncodeTo(&raw); err != nil {
+			return 0, err
+		}
+		return backend.Write(raw.Bytes())
+	}
 
 	// Proxy frontend -> backend
 	go func() {
-		var n int
-		var rerr, werr error
-
-		buf := make([]byte, 4096)
+		frontendReader := NewMessageReader(frontend)
 		for {
-			n, rerr = frontend.Read(buf)
-			s.transferred.Add(int64(n))
-			if n > 0 {
-				n, werr = backend.Write(buf[0:n])
-				if werr != nil {
-					errch <- werr
-					break
+			msg, err := readFrontendMessagePooled(frontendReader)
+			if err != nil {
+				errch <- err
+				return
+			}
+
+			// Retry needs a buffered copy of every message's raw bytes to
+			// replay later, so it takes the bytes.Buffer path; otherwise
+			// writeMsgToBackend writes straight through, using each
+			// message's FastEncoder where available.
+			if s.retryMaxAttempts == 0 {
+				n, err := writeMsgToBackend(msg)
+				s.transferred.Add(int64(n))
+				s.metrics.bytesTransferred.WithLabelValues("in").Add(float64(n))
+				if err != nil {
+					errch <- err
+					return
 				}
+				continue
+			}
+
+			var raw bytes.Buffer
+			if err := msg.EncodeTo(&raw); err != nil {
+				errch <- err
+				return
 			}
 
-			if rerr != nil {
-				break
+			unsafe := isUnsafeToRetry(msg)
+			switch msg.(type) {
+			case *CopyData, *CopyDone:
+				unsafe = true
+			}
+
+			switch msg.(type) {
+			case *Query, *Sync:
+				buf.noteSyncSent()
+			}
+
+			buf.append(raw.Bytes(), s.retryMaxBufferBytes, unsafe)
+
+			if err := writeToBackend(raw.Bytes()); err != nil {
+				errch <- err
+				return
 			}
 		}
 	}()
 
 	// Proxy backend -> frontend
 	go func() {
-		var n int
-		var rerr, werr error
+		backendReader := NewMessageReader(backend)
+		frontendWriter := NewMessageWriter(frontend)
+
+		// swallow counts the ReadyForQuery cycles still owed to a retry's
+		// replay: every msgs entry but the one that originally failed was
+		// already acknowledged to the client on its first pass, so those
+		// cycles must be consumed here rather than forwarded again.
+		swallow := 0
 
-		buf := make([]byte, 4096)
 		for {
-			n, rerr = backend.Read(buf)
-			s.transferred.Add(int64(n))
-			if n > 0 {
-				n, werr = frontend.Write(buf[0:n])
-				if werr != nil {
-					break
+			msg, err := readBackendMessagePooled(backendReader)
+			if err != nil {
+				errch <- err
+				return
+			}
+
+			switch m := msg.(type) {
+			case *ReadyForQuery:
+				buf.noteReadyForQuery()
+				if m.status == 'I' {
+					buf.reset()
+				}
+				if swallow > 0 {
+					swallow--
+					continue
+				}
+
+			case *ErrorResponse:
+				code := m.Code()
+				retryable := code == sqlStateSerializationFailure || code == sqlStateDeadlockDetected
+				if retryable && s.retryMaxAttempts > 0 {
+					if msgs, attempt, ok := buf.beginRetry(s.retryMaxAttempts); ok {
+						if n, ok := s.retryTransaction(backend, writeToBackend, msgs, attempt, code); ok {
+							swallow = n
+							continue
+						}
+					}
+				}
+			}
+
+			if swallow > 0 {
+				continue
+			}
+
+			// Anything but the closing ReadyForQuery that reaches this point
+			// is about to be forwarded to the client; see markResponseSent.
+			if _, ok := msg.(*ReadyForQuery); !ok {
+				buf.markResponseSent()
+			}
+
+			var n int
+			var werr error
+			if fe, ok := msg.(FastEncoder); ok {
+				n, werr = fe.EncodeToWriter(frontendWriter)
+			} else {
+				var raw bytes.Buffer
+				if err := msg.EncodeTo(&raw); err != nil {
+					errch <- err
+					return
 				}
+				n, werr = frontend.Write(raw.Bytes())
 			}
 
-			if rerr != nil {
-				errch <- rerr
-				break
+			s.transferred.Add(int64(n))
+			s.metrics.bytesTransferred.WithLabelValues("out").Add(float64(n))
+			if werr != nil {
+				errch <- werr
+				return
 			}
 		}
 	}()
@@ -20,6 +20,10 @@ type Config struct {
 		Primary  string
 		Follower string
 		Backends []string
+
+		// ReadonlyUsers lists database users who are always routed to a
+		// follower, even when they connect through the primary listener.
+		ReadonlyUsers []string
 	}
 
 	Health struct {
@@ -27,6 +31,42 @@ type Config struct {
 		Password string
 		Database string
 	}
+
+	TLS struct {
+		// CertFile and KeyFile, if both set, make the arbiter terminate TLS
+		// on its client-facing listeners instead of speaking plaintext.
+		CertFile string
+		KeyFile  string
+
+		// ClientCAFile, if set, requires and verifies a client certificate
+		// signed by this CA (mutual TLS) before completing the handshake.
+		ClientCAFile string
+
+		// BackendMode controls whether and how the arbiter re-originates
+		// TLS to backends: "" or "disable" (plaintext), "require" (TLS, no
+		// certificate verification) or "verify-full" (TLS, verified against
+		// BackendCAFile).
+		BackendMode string
+
+		// BackendCAFile is the CA backend server certificates are verified
+		// against when BackendMode is "verify-full".
+		BackendCAFile string
+	}
+
+	Retry struct {
+		// MaxAttempts is how many times the arbiter will roll back and
+		// replay a buffered transaction after a serialization_failure
+		// (40001) or deadlock_detected (40P01) error from the backend,
+		// before giving up and forwarding the error to the client.  0
+		// disables automatic retry.
+		MaxAttempts int
+
+		// MaxBufferBytes caps how much of a transaction's frontend
+		// messages the arbiter buffers for a possible replay.  A
+		// transaction whose buffered messages exceed this is still
+		// proxied normally, but can no longer be retried.
+		MaxBufferBytes int
+	}
 }
 
 func ConfigFromFile(filename string) (c *Config, err error) {
@@ -59,6 +99,13 @@ func ConfigFromFile(filename string) (c *Config, err error) {
 		}
 	}
 
+	if len(c.Main.ReadonlyUsers) > 0 {
+		c.Main.ReadonlyUsers = strings.Split(c.Main.ReadonlyUsers[0], ",")
+		for i := range c.Main.ReadonlyUsers {
+			c.Main.ReadonlyUsers[i] = strings.TrimSpace(c.Main.ReadonlyUsers[i])
+		}
+	}
+
 	if c.Health.Username == "" {
 		return nil, newConfigError("No health-check username defined")
 	}
@@ -67,5 +114,29 @@ func ConfigFromFile(filename string) (c *Config, err error) {
 		return nil, newConfigError("No health-check database defined")
 	}
 
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		return nil, newConfigError("TLS.CertFile and TLS.KeyFile must both be set, or both be empty")
+	}
+
+	switch c.TLS.BackendMode {
+	case "", "disable", "require", "verify-full":
+	default:
+		return nil, newConfigError("TLS.BackendMode must be one of disable, require, verify-full, got '%s'", c.TLS.BackendMode)
+	}
+
+	if c.TLS.BackendMode == "verify-full" && c.TLS.BackendCAFile == "" {
+		return nil, newConfigError("TLS.BackendCAFile must be set when TLS.BackendMode is verify-full")
+	}
+
+	if c.Retry.MaxAttempts < 0 {
+		return nil, newConfigError("Retry.MaxAttempts must not be negative")
+	}
+
+	if c.Retry.MaxBufferBytes == 0 {
+		c.Retry.MaxBufferBytes = 1 << 20 // 1 MiB
+	} else if c.Retry.MaxBufferBytes < 0 {
+		return nil, newConfigError("Retry.MaxBufferBytes must not be negative")
+	}
+
 	return c, nil
 }
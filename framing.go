@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"sync"
+)
+
+// bufSizeClasses are the scratch buffer sizes framing pools, in ascending
+// order.  getBuf picks the smallest class that fits the requested size;
+// anything larger than the last class is allocated directly and never
+// pooled.
+var bufSizeClasses = []int{64, 256, 4096, 16384, 65536}
+
+var bufPools = newBufPools()
+
+func newBufPools() []*sync.Pool {
+	pools := make([]*sync.Pool, len(bufSizeClasses))
+	for i := range bufSizeClasses {
+		sz := bufSizeClasses[i]
+		pools[i] = &sync.Pool{
+			New: func() interface{} {
+				buf := make([]byte, sz)
+				return &buf
+			},
+		}
+	}
+
+	return pools
+}
+
+// getBuf returns a []byte of length n, drawn from the smallest size class
+// that fits it.  Pair every call with putBuf once n is no longer needed.
+func getBuf(n int) []byte {
+	for i, sz := range bufSizeClasses {
+		if n <= sz {
+			buf := bufPools[i].Get().(*[]byte)
+			return (*buf)[:n]
+		}
+	}
+
+	return make([]byte, n)
+}
+
+// putBuf returns buf to the pool it was drawn from.  A buf whose capacity
+// doesn't match one of bufSizeClasses (i.e. one getBuf had to allocate
+// fresh for) is simply dropped.
+func putBuf(buf []byte) {
+	c := cap(buf)
+	for i, sz := range bufSizeClasses {
+		if c == sz {
+			full := buf[:sz]
+			bufPools[i].Put(&full)
+			return
+		}
+	}
+}
+
+// MessageReader wraps an io.Reader, decoding length-prefixed PostgreSQL
+// messages into scratch buffers drawn from framing's size-classed pool
+// instead of allocating a fresh []byte per message.
+//
+// Message implementations that want the allocation savings implement
+// DecodeFromReader(*MessageReader), which must only use the body passed to
+// its callback for the duration of that callback - ReadMessage returns the
+// buffer to the pool the instant the callback returns.  Anything that needs
+// to retain bytes past that (e.g. for a Raw() accessor) must copy them, and
+// should instead use the legacy DecodeFrom(io.Reader), whose buffer is
+// allocated fresh per call and is always safe to keep.
+type MessageReader struct {
+	r io.Reader
+}
+
+// NewMessageReader wraps r for pooled-buffer message decoding.
+func NewMessageReader(r io.Reader) *MessageReader {
+	return &MessageReader{r: r}
+}
+
+// ReadMessage reads one length-prefixed message body off mr into a pooled
+// scratch buffer and invokes fn with it, returning the buffer to the pool
+// as soon as fn returns.
+func (mr *MessageReader) ReadMessage(fn func(body []byte) error) (msglen int32, err error) {
+	var header [4]byte
+	if _, err = io.ReadFull(mr.r, header[:]); err != nil {
+		return 0, err
+	}
+	msglen = int32(binary.BigEndian.Uint32(header[:]))
+
+	body := getBuf(int(msglen) - 4)
+	defer putBuf(body)
+
+	if _, err = io.ReadFull(mr.r, body); err != nil {
+		return msglen, err
+	}
+
+	return msglen, fn(body)
+}
+
+// FastDecoder is implemented by Message types that offer a
+// DecodeFromReader fast path on top of their regular DecodeFrom(io.Reader).
+type FastDecoder interface {
+	DecodeFromReader(mr *MessageReader) error
+}
+
+// MessageWriter wraps an io.Writer, handing a message's type byte, length
+// and fields to a single net.Buffers write instead of one Write call per
+// field.
+type MessageWriter struct {
+	w io.Writer
+}
+
+// NewMessageWriter wraps w for coalesced message writes.
+func NewMessageWriter(w io.Writer) *MessageWriter {
+	return &MessageWriter{w: w}
+}
+
+// WriteMessage writes a message of the given type with fields as its body
+// via net.Buffers: a single writev when w is a *net.TCPConn or similar, and
+// one Write per buffer for any other io.Writer (e.g. a *tls.Conn).  It
+// returns the total number of bytes written, type byte included.
+func (mw *MessageWriter) WriteMessage(msgType byte, fields ...[]byte) (int, error) {
+	var n int32 = 4
+	for _, f := range fields {
+		n += int32(len(f))
+	}
+
+	var header [5]byte
+	header[0] = msgType
+	binary.BigEndian.PutUint32(header[1:], uint32(n))
+
+	buffers := make(net.Buffers, 0, len(fields)+1)
+	buffers = append(buffers, header[:])
+	buffers = append(buffers, fields...)
+
+	written, err := buffers.WriteTo(mw.w)
+	return int(written), err
+}
+
+// FastEncoder is implemented by Message types that offer an EncodeToWriter
+// fast path on top of their regular EncodeTo(io.Writer).  EncodeToWriter
+// returns the number of bytes written, for callers tracking transfer metrics.
+type FastEncoder interface {
+	EncodeToWriter(mw *MessageWriter) (int, error)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+// simpleQueryCycleBytes builds the backend side of one Simple Query cycle
+// (RowDescription, a handful of DataRows, CommandComplete, ReadyForQuery) as
+// it would appear on the wire.
+func simpleQueryCycleBytes(tb testing.TB) []byte {
+	tb.Helper()
+
+	var buf bytes.Buffer
+
+	rowDesc := &RowDescription{
+		Fields: []FieldDescription{
+			{Name: "id", DataTypeOID: 23, DataTypeSize: 4, Format: 0},
+		},
+	}
+	if err := rowDesc.EncodeTo(&buf); err != nil {
+		tb.Fatalf("encoding RowDescription: %v", err)
+	}
+
+	for i := 0; i < 10; i++ {
+		row := &DataRow{Values: [][]byte{[]byte("1")}}
+		if err := row.EncodeTo(&buf); err != nil {
+			tb.Fatalf("encoding DataRow: %v", err)
+		}
+	}
+
+	cmdComplete := CommandComplete("SELECT 10")
+	if err := cmdComplete.EncodeTo(&buf); err != nil {
+		tb.Fatalf("encoding CommandComplete: %v", err)
+	}
+
+	if err := (&ReadyForQuery{status: 'I'}).EncodeTo(&buf); err != nil {
+		tb.Fatalf("encoding ReadyForQuery: %v", err)
+	}
+
+	return buf.Bytes()
+}
+
+// readSimpleQueryCycle reads the 13 messages of one Simple Query cycle built
+// by simpleQueryCycleBytes (RowDescription, 10 DataRows, CommandComplete,
+// ReadyForQuery) off r via readFn, discarding them.
+func readSimpleQueryCycle(tb testing.TB, r *bytes.Reader, readFn func() (Message, error)) {
+	tb.Helper()
+
+	for i := 0; i < 13; i++ {
+		if _, err := readFn(); err != nil {
+			tb.Fatalf("reading message %d: %v", i, err)
+		}
+	}
+}
+
+// BenchmarkSimpleQueryCycleLegacy measures allocations decoding a Simple
+// Query cycle via the legacy, per-message-allocating DecodeFrom path.
+func BenchmarkSimpleQueryCycleLegacy(b *testing.B) {
+	raw := simpleQueryCycleBytes(b)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		r := bytes.NewReader(raw)
+		readSimpleQueryCycle(b, r, func() (Message, error) {
+			return readBackendMessage(r)
+		})
+	}
+}
+
+// BenchmarkSimpleQueryCyclePooled measures allocations decoding the same
+// cycle via the pooled-buffer fast path, readBackendMessagePooled.
+func BenchmarkSimpleQueryCyclePooled(b *testing.B) {
+	raw := simpleQueryCycleBytes(b)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		r := bytes.NewReader(raw)
+		mr := NewMessageReader(r)
+		readSimpleQueryCycle(b, r, func() (Message, error) {
+			return readBackendMessagePooled(mr)
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+// frontendMessageTypes mirrors the type bytes frontendMessageBuilder
+// dispatches on. TestFrontendMessageEncodeToWireType checks that every
+// message's EncodeTo writes back the same type byte it was built under -
+// the CopyFail/Parse mixup this test was added for went unnoticed because
+// nothing checked that round-trip.
+var frontendMessageTypes = []byte{
+	'B', 'C', 'D', 'E', 'F', 'H', 'P', 'Q', 'S', 'X', 'c', 'd', 'f', 'p',
+}
+
+func TestFrontendMessageEncodeToWireType(t *testing.T) {
+	for _, want := range frontendMessageTypes {
+		msg, err := frontendMessageBuilder(want)
+		if err != nil {
+			t.Fatalf("building message for type %q: %v", want, err)
+		}
+
+		var buf bytes.Buffer
+		if err := msg.EncodeTo(&buf); err != nil {
+			t.Fatalf("encoding zero-value %T: %v", msg, err)
+		}
+
+		if got := buf.Bytes()[0]; got != want {
+			t.Errorf("%T.EncodeTo wrote type byte %q, want %q (the byte it's dispatched under in frontendMessageBuilder)", msg, got, want)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/solvip/arbiter/pool"
+)
+
+// promMetrics implements pool.Metrics on top of Prometheus collectors, and
+// also exposes the connection-level counters startListener and proxy update
+// directly, so every atomic the arbiter already tracks becomes Prom-visible.
+type promMetrics struct {
+	backendState     *prometheus.GaugeVec
+	backendLatency   *prometheus.GaugeVec
+	backendAvailable *prometheus.GaugeVec
+	pingFailures     *prometheus.CounterVec
+	dialFailures     *prometheus.CounterVec
+	failCalls        *prometheus.CounterVec
+
+	poolAvailable  prometheus.Gauge
+	poolHasPrimary prometheus.Gauge
+
+	poolIdleConns  *prometheus.GaugeVec
+	poolInUseConns *prometheus.GaugeVec
+	poolDialErrors *prometheus.GaugeVec
+
+	connectionsActive prometheus.Gauge
+	bytesTransferred  *prometheus.CounterVec
+
+	transactionRetries *prometheus.CounterVec
+}
+
+// newPromMetrics builds and registers every arbiter collector against the
+// default registry.
+func newPromMetrics() *promMetrics {
+	m := &promMetrics{
+		backendState: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "arbiter_backend_state",
+			Help: "Current state of a backend: 0=unavailable, 1=read_only, 2=read_write.",
+		}, []string{"addr"}),
+
+		backendLatency: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "arbiter_backend_latency_seconds",
+			Help: "Latency of the most recent health check against a backend.",
+		}, []string{"addr"}),
+
+		backendAvailable: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "arbiter_backend_available",
+			Help: "Whether a backend is currently available: 1=yes, 0=no.",
+		}, []string{"addr"}),
+
+		pingFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "arbiter_backend_ping_failures_total",
+			Help: "Total number of failed health checks against a backend.",
+		}, []string{"addr"}),
+
+		dialFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "arbiter_backend_dial_failures_total",
+			Help: "Total number of failed connection attempts to a backend.",
+		}, []string{"addr"}),
+
+		failCalls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "arbiter_backend_fail_calls_total",
+			Help: "Total number of times Fail() was called on a backend.",
+		}, []string{"addr"}),
+
+		poolAvailable: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "arbiter_pool_available",
+			Help: "Number of backends currently available in the pool.",
+		}),
+
+		poolHasPrimary: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "arbiter_pool_has_primary",
+			Help: "Whether the pool currently has a primary: 1=yes, 0=no.",
+		}),
+
+		poolIdleConns: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "arbiter_backend_pool_idle_connections",
+			Help: "Number of dialed-ahead, unconsumed connections currently warm in a backend's pool.",
+		}, []string{"addr"}),
+
+		poolInUseConns: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "arbiter_backend_pool_in_use_connections",
+			Help: "Number of connections handed out by Connect that haven't yet been closed.",
+		}, []string{"addr"}),
+
+		poolDialErrors: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "arbiter_backend_pool_dial_errors",
+			Help: "Running count of failed connection attempts to a backend.",
+		}, []string{"addr"}),
+
+		connectionsActive: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "arbiter_connections_active",
+			Help: "Number of client connections currently being proxied.",
+		}),
+
+		bytesTransferred: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "arbiter_bytes_transferred_total",
+			Help: "Total number of bytes proxied between clients and backends.",
+		}, []string{"direction"}),
+
+		transactionRetries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "arbiter_transaction_retries_total",
+			Help: "Total number of buffered transactions rolled back and replayed after a serialization failure or deadlock.",
+		}, []string{"sqlstate"}),
+	}
+
+	prometheus.MustRegister(
+		m.backendState, m.backendLatency, m.backendAvailable,
+		m.pingFailures, m.dialFailures, m.failCalls,
+		m.poolAvailable, m.poolHasPrimary,
+		m.poolIdleConns, m.poolInUseConns, m.poolDialErrors,
+		m.connectionsActive, m.bytesTransferred,
+		m.transactionRetries,
+	)
+
+	return m
+}
+
+// BackendState implements pool.Metrics.
+func (m *promMetrics) BackendState(addr string, state pool.State, latency time.Duration) {
+	m.backendState.WithLabelValues(addr).Set(float64(state))
+	m.backendLatency.WithLabelValues(addr).Set(latency.Seconds())
+
+	available := 0.0
+	if state != pool.UNAVAILABLE {
+		available = 1.0
+	}
+	m.backendAvailable.WithLabelValues(addr).Set(available)
+}
+
+// PingFailure implements pool.Metrics.
+func (m *promMetrics) PingFailure(addr string) {
+	m.pingFailures.WithLabelValues(addr).Inc()
+}
+
+// DialFailure implements pool.Metrics.
+func (m *promMetrics) DialFailure(addr string) {
+	m.dialFailures.WithLabelValues(addr).Inc()
+}
+
+// BackendFailed implements pool.Metrics.
+func (m *promMetrics) BackendFailed(addr string) {
+	m.failCalls.WithLabelValues(addr).Inc()
+}
+
+// transactionRetried records that a buffered transaction was rolled back and
+// replayed against its backend after an ErrorResponse carrying sqlstate.
+func (m *promMetrics) transactionRetried(sqlstate string) {
+	m.transactionRetries.WithLabelValues(sqlstate).Inc()
+}
+
+// PoolStatus implements pool.Metrics.
+func (m *promMetrics) PoolStatus(available int, hasPrimary bool) {
+	m.poolAvailable.Set(float64(available))
+
+	v := 0.0
+	if hasPrimary {
+		v = 1.0
+	}
+	m.poolHasPrimary.Set(v)
+}
+
+// WarmPoolStats implements pool.Metrics.
+func (m *promMetrics) WarmPoolStats(addr string, idle, inUse int, dialErrors int64) {
+	m.poolIdleConns.WithLabelValues(addr).Set(float64(idle))
+	m.poolInUseConns.WithLabelValues(addr).Set(float64(inUse))
+	m.poolDialErrors.WithLabelValues(addr).Set(float64(dialErrors))
+}
@@ -30,4 +30,18 @@ type Backend interface {
 	// Fail closes all connections to a specified backend.  It should be called by a client
 	// that cannot write to or read from a connection previously returned by Connect().
 	Fail()
+
+	// Notify returns a channel that a backend may use to signal that it should be
+	// re-checked immediately, without waiting for the next scheduled Ping.  A nil
+	// value will never be sent; Pool only uses the channel as a select trigger.
+	Notify() <-chan State
+}
+
+// connStats is implemented by backends that track connection-pool depth,
+// such as pg's warm pool.  Pool type-asserts for it after every Ping and, if
+// present, forwards the numbers to Metrics.WarmPoolStats.
+type connStats interface {
+	IdleConns() int
+	InUseConns() int
+	DialErrors() int64
 }
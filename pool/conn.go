@@ -10,6 +10,26 @@ import (
 type Conn struct {
 	underlying    net.Conn
 	closeHandlers []func()
+
+	// dialedAt records when the underlying socket was dialed, so a backend's
+	// warm pool can cap how long an unused connection is allowed to sit idle.
+	dialedAt time.Time
+
+	// consumed is set once this Conn has been handed to a frontend proxy
+	// loop.  A Conn that was never consumed may still be recycled back into
+	// a backend's warm pool on Close; one that was must not be, since reuse
+	// of a socket a client has authenticated over is unsafe.
+	consumed bool
+
+	// recycled is set by a close handler that returned this Conn to a pool
+	// instead of tearing down the underlying socket.
+	recycled bool
+}
+
+// Consume marks this Conn as handed off to a frontend proxy loop, so it will
+// never be recycled into a backend's warm pool.
+func (c *Conn) Consume() {
+	c.consumed = true
 }
 
 func (c *Conn) Read(b []byte) (n int, err error) {
@@ -24,6 +44,11 @@ func (c *Conn) Close() error {
 	for _, h := range c.closeHandlers {
 		h()
 	}
+
+	if c.recycled {
+		return nil
+	}
+
 	return c.underlying.Close()
 }
 
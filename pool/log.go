@@ -0,0 +1,30 @@
+package pool
+
+import "go.uber.org/zap"
+
+// Logger is the structured logger used by Pool and its backends.  Every
+// state transition, ping failure, dial error and proxy error is logged as a
+// single event with typed fields via Infow/Warnw/Errorw.  Printf exists
+// purely as a shim so call sites that haven't been converted to typed
+// fields yet can migrate mechanically.
+type Logger interface {
+	Infow(msg string, keysAndValues ...interface{})
+	Warnw(msg string, keysAndValues ...interface{})
+	Errorw(msg string, keysAndValues ...interface{})
+	Printf(format string, args ...interface{})
+}
+
+// sugaredLogger adapts a *zap.SugaredLogger to Logger; Infow/Warnw/Errorw
+// are already satisfied directly, so only the Printf shim needs adding.
+type sugaredLogger struct {
+	*zap.SugaredLogger
+}
+
+func (s sugaredLogger) Printf(format string, args ...interface{}) {
+	s.Infof(format, args...)
+}
+
+// NewLogger wraps a *zap.Logger for use by Pool and NewPostgresBackend.
+func NewLogger(l *zap.Logger) Logger {
+	return sugaredLogger{l.Sugar()}
+}
@@ -0,0 +1,41 @@
+package pool
+
+import "time"
+
+// Metrics receives instrumentation events from a Pool and its backends, so a
+// caller can expose them however it likes (Prometheus, statsd, ...) without
+// this package taking a hard dependency on any particular library.
+type Metrics interface {
+	// BackendState reports a backend's most recently observed state and
+	// health-check latency.
+	BackendState(addr string, state State, latency time.Duration)
+
+	// PingFailure is called every time a backend's health check fails.
+	PingFailure(addr string)
+
+	// DialFailure is called every time a connection attempt to a backend fails.
+	DialFailure(addr string)
+
+	// BackendFailed is called every time Fail() is invoked on a backend.
+	BackendFailed(addr string)
+
+	// PoolStatus reports how many backends are currently available, and
+	// whether one of them is the primary.
+	PoolStatus(available int, hasPrimary bool)
+
+	// WarmPoolStats reports a backend's connection-pool depth: idle
+	// (dialed-ahead, unconsumed) connections, in-use connections handed
+	// out by Connect, and the running count of failed dial attempts.
+	WarmPoolStats(addr string, idle, inUse int, dialErrors int64)
+}
+
+// NopMetrics discards every event.  It's a convenient Metrics to pass from
+// callers (and tests) that don't care about instrumentation.
+type NopMetrics struct{}
+
+func (NopMetrics) BackendState(addr string, state State, latency time.Duration) {}
+func (NopMetrics) PingFailure(addr string)                                      {}
+func (NopMetrics) DialFailure(addr string)                                      {}
+func (NopMetrics) BackendFailed(addr string)                                    {}
+func (NopMetrics) PoolStatus(available int, hasPrimary bool)                    {}
+func (NopMetrics) WarmPoolStats(addr string, idle, inUse int, dialErrors int64) {}
@@ -4,7 +4,7 @@ package pool
 import (
 	"errors"
 	"fmt"
-	"log"
+	"math/rand"
 	"sort"
 	"sync"
 	"time"
@@ -12,21 +12,39 @@ import (
 
 var ErrNoneAvailable = errors.New("no backend available")
 
+// normalProbeInterval is how often a healthy backend is pinged.
+const normalProbeInterval = time.Second
+
 type member struct {
 	b     Backend
 	state State
 	lat   time.Duration
+
+	backoff   backoff
+	nextProbe time.Time
 }
 
 func (m member) String() string {
 	return fmt.Sprintf("member[addr: %s, state = %s, latency = %s]", m.b.Addr(), m.state, m.lat)
 }
 
+// BackendStatus is a snapshot of a member's health, suitable for exposing
+// over /stats.
+type BackendStatus struct {
+	Addr      string
+	State     State
+	Latency   time.Duration
+	NextProbe time.Time
+}
+
 type Pool struct {
 	sync.RWMutex
 
+	logger  Logger
+	metrics Metrics
+
 	// all members registered to this pool.
-	members []member
+	members []*member
 
 	// all available members; always ordered by latency.
 	avail []*member
@@ -35,19 +53,39 @@ type Pool struct {
 	primary *member
 }
 
-// Return a new pool
-func New() *Pool {
-	return &Pool{}
+// Return a new pool, logging every state transition to logger and reporting
+// every state transition and pool-level change to metrics.
+func New(logger Logger, metrics Metrics) *Pool {
+	return &Pool{logger: logger, metrics: metrics}
 }
 
 func (p *Pool) Put(backend Backend) {
 	p.Lock()
 	defer p.Unlock()
 
-	b := member{b: backend}
+	m := &member{b: backend}
+
+	p.members = append(p.members, m)
+	go p.monitor(m)
+}
+
+// Status returns a point-in-time snapshot of every backend registered with
+// the pool, including backends that are currently UNAVAILABLE and backing off.
+func (p *Pool) Status() []BackendStatus {
+	p.RLock()
+	defer p.RUnlock()
+
+	ret := make([]BackendStatus, len(p.members))
+	for i, m := range p.members {
+		ret[i] = BackendStatus{
+			Addr:      m.b.Addr(),
+			State:     m.state,
+			Latency:   m.lat,
+			NextProbe: m.nextProbe,
+		}
+	}
 
-	p.members = append(p.members, b)
-	go p.monitor(&b)
+	return ret
 }
 
 // Get a member; can return any - including the primary.
@@ -76,14 +114,34 @@ func (p *Pool) GetForWrite() (b Backend, err error) {
 
 // Monitor a member
 func (p *Pool) monitor(m *member) {
-	ticker := time.NewTicker(time.Second)
-	defer ticker.Stop()
+	timer := time.NewTimer(normalProbeInterval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+		case <-m.b.Notify():
+			// A backend signalled an out-of-band state change (e.g. via
+			// Postgres LISTEN/NOTIFY); re-run the recovery check now
+			// instead of waiting for the next scheduled probe.
+			if !timer.Stop() {
+				<-timer.C
+			}
+		}
 
-	for _ = range ticker.C {
 		start := time.Now()
 		newstate, err := m.b.Ping()
 		lat := time.Since(start)
 
+		if err != nil {
+			p.metrics.PingFailure(m.b.Addr())
+			p.logger.Warnw("backend ping failed",
+				"backend_addr", m.b.Addr(),
+				"latency_ms", lat.Seconds()*1000,
+				"err", err,
+			)
+		}
+
 		p.Lock()
 
 		switch {
@@ -123,14 +181,38 @@ func (p *Pool) monitor(m *member) {
 		}
 
 		if m.state != newstate {
-			log.Printf("%s: transitioning to %s", m, newstate)
+			p.logger.Infow("backend state transition",
+				"backend_addr", m.b.Addr(),
+				"from_state", m.state,
+				"to_state", newstate,
+				"latency_ms", lat.Seconds()*1000,
+				"err", err,
+			)
 		}
 
 		m.state = newstate
 		m.lat = lat
 		sort.Sort(byLatency(p.avail))
 
+		p.metrics.BackendState(m.b.Addr(), newstate, lat)
+		p.metrics.PoolStatus(len(p.avail), p.primary != nil)
+
+		if cs, ok := m.b.(connStats); ok {
+			p.metrics.WarmPoolStats(m.b.Addr(), cs.IdleConns(), cs.InUseConns(), cs.DialErrors())
+		}
+
+		var interval time.Duration
+		if newstate == UNAVAILABLE {
+			interval = m.backoff.next()
+		} else {
+			m.backoff.reset()
+			interval = normalProbeInterval
+		}
+		m.nextProbe = time.Now().Add(interval)
+
 		p.Unlock()
+
+		timer.Reset(interval)
 	}
 }
 
@@ -140,6 +222,51 @@ func (coll byLatency) Len() int           { return len(coll) }
 func (coll byLatency) Swap(i, j int)      { coll[i], coll[j] = coll[j], coll[i] }
 func (coll byLatency) Less(i, j int) bool { return coll[i].lat < coll[j].lat }
 
+// backoff tracks the current retry interval for a backend that's failing its
+// health checks.  It starts at backoffInitial, doubles on every consecutive
+// failure up to backoffMax, and resets once the backend is healthy again.
+type backoff struct {
+	interval time.Duration
+}
+
+const (
+	backoffInitial = 100 * time.Millisecond
+	backoffFactor  = 2
+	backoffMax     = 30 * time.Second
+)
+
+// next returns the next interval to wait before probing again, and advances
+// the backoff state.
+func (b *backoff) next() time.Duration {
+	if b.interval == 0 {
+		b.interval = backoffInitial
+	} else if b.interval < backoffMax {
+		b.interval *= backoffFactor
+		if b.interval > backoffMax {
+			b.interval = backoffMax
+		}
+	}
+
+	return jitter(b.interval)
+}
+
+// reset clears the backoff state, so the next call to next() starts over at
+// backoffInitial.
+func (b *backoff) reset() {
+	b.interval = 0
+}
+
+// jitter returns d +/- 20%, so that a fleet of backends failing at the same
+// time doesn't re-probe in lockstep.
+func jitter(d time.Duration) time.Duration {
+	spread := int64(d) / 5
+	if spread <= 0 {
+		return d
+	}
+
+	return d - time.Duration(spread) + time.Duration(rand.Int63n(2*spread+1))
+}
+
 // Opposite of append.  Remove it from s, returning s - it.
 func remove(s []*member, it *member) (ret []*member) {
 	for _, v := range s {
@@ -4,10 +4,16 @@ import (
 	"errors"
 	"testing"
 	"time"
+
+	"go.uber.org/zap"
 )
 
+func testLogger() Logger {
+	return NewLogger(zap.NewNop())
+}
+
 func TestEmptyPool(t *testing.T) {
-	emptyPool := New()
+	emptyPool := New(testLogger(), NopMetrics{})
 
 	b, err := emptyPool.GetForRead()
 	if b != nil || err != ErrNoneAvailable {
@@ -21,7 +27,7 @@ func TestEmptyPool(t *testing.T) {
 }
 
 func TestGet(t *testing.T) {
-	p := New()
+	p := New(testLogger(), NopMetrics{})
 
 	a := &mockend{state: READ_ONLY, id: "a"}
 	b := &mockend{state: READ_WRITE, id: "b"}
@@ -49,7 +55,7 @@ func TestGet(t *testing.T) {
 }
 
 func TestFail(t *testing.T) {
-	p := New()
+	p := New(testLogger(), NopMetrics{})
 
 	// Since the initial state of all backends in pool is unavailable, allow one health
 	// check to succeed.
@@ -94,6 +100,10 @@ func (m *mockend) Addr() string {
 	return "foo"
 }
 
-func (m *mockend) Connect(t time.Duration) (c Conn, err error) {
+func (m *mockend) Notify() <-chan State {
+	return nil
+}
+
+func (m *mockend) Connect(t time.Duration) (c *Conn, err error) {
 	return c, err
 }
@@ -2,34 +2,128 @@ package pool
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 	"net"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// ErrBackingOff is returned by Connect when a backend has seen repeated
+// dial failures and is still within its backoff window; the caller should
+// treat this the same as a dial error without retrying immediately.
+var ErrBackingOff = errors.New("backend is backing off after repeated dial failures")
+
+// notifyChannel is the Postgres NOTIFY channel that pg listens on for
+// out-of-band state transitions.  Operators (or a trigger installed by
+// an `arbiter migrate` helper) can NOTIFY this channel with a payload of
+// 'promoted', 'demoted' or 'shutdown' to make a failover visible to the
+// arbiter immediately instead of on the next poll.
+const notifyChannel = "arbiter_state"
+
+// minReconnectInterval and maxReconnectInterval govern the backoff pq.Listener
+// applies while re-establishing a dropped listener connection.
+const (
+	minReconnectInterval = 20 * time.Millisecond
+	maxReconnectInterval = time.Hour
+)
+
+// warmPoolSize is how many dialed-ahead, not-yet-consumed connections a
+// backend tries to keep on hand, and warmPoolMaxSize is the hard cap on how
+// many it will hold (e.g. if Close() returns several in a row).
+// warmConnMaxIdle bounds how long a warm connection may sit dialed but
+// unused. A warm socket hasn't sent a startup packet yet, so it's the
+// server's authentication_timeout (60s by default) that governs how long
+// Postgres will keep it open, not idle_in_transaction_session_timeout;
+// warmConnMaxIdle is kept well under that default so takeWarm never hands
+// out a connection the server has already closed.
+const (
+	warmPoolSize    = 4
+	warmPoolMaxSize = 64
+	warmConnMaxIdle = 20 * time.Second
+	warmDialTimeout = 5 * time.Second
+)
+
 // pg is the Postgres implementation of a Backend
 type pg struct {
 	db         *sql.DB
 	address    string
 	connstring string
+	logger     Logger
+	metrics    Metrics
+
+	// inflightMu guards inflight: Connect and the per-conn close handler
+	// write it from client-handling goroutines, Fail() reads it from the
+	// pool's monitor goroutine, and they all run concurrently.
+	inflightMu sync.Mutex
 	inflight   map[*Conn]bool
+
+	listenerMu sync.Mutex
+	listener   *pq.Listener
+	notify     chan State
+
+	dialMu      sync.Mutex
+	dialBackoff backoff
+	nextDialAt  time.Time
+	dialErrors  int64
+
+	warmMu sync.Mutex
+	warm   []*Conn
 }
 
-func NewPostgresBackend(address, user, pass, database string) *pg {
+func NewPostgresBackend(address, user, pass, database string, logger Logger, metrics Metrics) *pg {
 	connstring := fmt.Sprintf("postgres://%s:%s@%s/%s?connect_timeout=5&sslmode=disable",
 		user, pass, address, database)
-	return &pg{
+
+	p := &pg{
 		inflight:   make(map[*Conn]bool),
 		address:    address,
 		connstring: connstring,
+		notify:     make(chan State, 1),
+		logger:     logger,
+		metrics:    metrics,
+	}
+
+	p.ensureListener()
+	go p.fillWarmPool()
+
+	return p
+}
+
+// ensureListener (re-)establishes the LISTEN/NOTIFY path if it isn't already
+// running.  It is called from NewPostgresBackend and again every time Ping
+// observes the backend alive, since Fail() tears the listener down.  Both
+// run on goroutines distinct from each other (and from the per-connection
+// goroutines that call Fail() directly), so p.listener goes through
+// listenerMu rather than being read or written bare.
+func (p *pg) ensureListener() {
+	p.listenerMu.Lock()
+	defer p.listenerMu.Unlock()
+
+	if p.listener != nil {
+		return
+	}
+
+	l := pq.NewListener(p.connstring, minReconnectInterval, maxReconnectInterval, p.listenerEvent)
+	if err := l.Listen(notifyChannel); err != nil {
+		p.logger.Warnw("could not subscribe to notification channel",
+			"backend_addr", p.address, "channel", notifyChannel, "err", err)
 	}
+	p.listener = l
+	go p.listen(l)
 }
 
 func (p *pg) Addr() string {
 	return p.address
 }
 
+// Notify implements Backend.
+func (p *pg) Notify() <-chan State {
+	return p.notify
+}
+
 func (p *pg) Ping() (s State, err error) {
 	// Ensure that the monitoring connection is alive
 	if p.db == nil {
@@ -37,15 +131,22 @@ func (p *pg) Ping() (s State, err error) {
 		if err != nil {
 			return s, err
 		}
-	}
 
-	p.db.SetMaxOpenConns(1)
+		p.db.SetMaxOpenConns(1)
+	}
 
 	if err = p.db.Ping(); err != nil {
 		return s, err
 	}
 
-	// Check if we're a primary or a follower
+	p.ensureListener()
+
+	return p.queryState()
+}
+
+// queryState assumes p.db is already alive and asks the backend whether it's
+// currently a primary or a follower.
+func (p *pg) queryState() (s State, err error) {
 	var inRecovery bool
 	row := p.db.QueryRow("select pg_is_in_recovery();")
 	if err = row.Scan(&inRecovery); err != nil {
@@ -59,17 +160,199 @@ func (p *pg) Ping() (s State, err error) {
 	}
 }
 
-func (p *pg) Connect(t time.Duration) (conn *Conn, err error) {
+// listen forwards every notification received on l into p.notify, triggering
+// an immediate recovery check in Pool.monitor.  It returns once l is closed,
+// i.e. after Fail().  l is passed in rather than read off p.listener so this
+// goroutine never touches that field without listenerMu held.
+func (p *pg) listen(l *pq.Listener) {
+	for range l.Notify {
+		p.wake()
+	}
+}
+
+// wake pokes p.notify without blocking.  The actual State carried is
+// irrelevant; Pool.monitor treats the channel purely as a wake-up and always
+// re-runs Ping itself to learn the real state.
+func (p *pg) wake() {
+	select {
+	case p.notify <- UNAVAILABLE:
+	default:
+		// A check is already pending; no need to queue another.
+	}
+}
+
+// listenerEvent is pq.Listener's event callback.  A dropped connection simply
+// means state transitions will be observed on the next poll instead of
+// instantly, until the listener reconnects.
+func (p *pg) listenerEvent(ev pq.ListenerEventType, err error) {
+	switch ev {
+	case pq.ListenerEventDisconnected:
+		p.logger.Warnw("notification listener disconnected, falling back to polling",
+			"backend_addr", p.address, "err", err)
+	case pq.ListenerEventReconnected:
+		p.logger.Infow("notification listener reconnected", "backend_addr", p.address)
+		p.wake()
+	case pq.ListenerEventConnectionAttemptFailed:
+		p.logger.Warnw("notification listener reconnect attempt failed",
+			"backend_addr", p.address, "err", err)
+	}
+}
+
+// dialConn dials a single fresh connection to this backend.
+func (p *pg) dialConn(t time.Duration) (conn *Conn, err error) {
 	conn = new(Conn)
 	conn.underlying, err = net.DialTimeout("tcp", p.Addr(), t)
 	if err != nil {
-		p.Fail()
-		return conn, err
+		return nil, err
+	}
+
+	conn.dialedAt = time.Now()
+	return conn, nil
+}
+
+// takeWarm returns a dialed-ahead connection from the warm pool, or nil if
+// none are available.  Connections that have sat idle past warmConnMaxIdle
+// are discarded rather than handed out.
+func (p *pg) takeWarm() *Conn {
+	p.warmMu.Lock()
+	defer p.warmMu.Unlock()
+
+	for len(p.warm) > 0 {
+		conn := p.warm[len(p.warm)-1]
+		p.warm = p.warm[:len(p.warm)-1]
+
+		if time.Since(conn.dialedAt) > warmConnMaxIdle {
+			conn.underlying.Close()
+			continue
+		}
+
+		return conn
+	}
+
+	return nil
+}
+
+// recycle offers conn back to the warm pool.  It refuses (and the caller
+// must close the socket) once the pool is at warmPoolMaxSize.
+func (p *pg) recycle(conn *Conn) bool {
+	p.warmMu.Lock()
+	defer p.warmMu.Unlock()
+
+	if len(p.warm) >= warmPoolMaxSize {
+		return false
+	}
+
+	p.warm = append(p.warm, conn)
+	return true
+}
+
+// fillWarmPool keeps up to warmPoolSize dialed-ahead connections ready so
+// that Connect can hand one out without paying for a TCP handshake on the
+// client's accept path; the Postgres startup exchange still runs after the
+// connection is handed out.  It backs off
+// using the same dial backoff Connect uses, so a dead backend isn't dialed
+// in a tight loop.
+func (p *pg) fillWarmPool() {
+	for {
+		p.warmMu.Lock()
+		idle := len(p.warm)
+		p.warmMu.Unlock()
+
+		if idle >= warmPoolSize {
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+
+		p.dialMu.Lock()
+		wait := time.Until(p.nextDialAt)
+		p.dialMu.Unlock()
+
+		if wait > 0 {
+			time.Sleep(wait)
+			continue
+		}
+
+		conn, err := p.dialConn(warmDialTimeout)
+		if err != nil {
+			atomic.AddInt64(&p.dialErrors, 1)
+			p.metrics.DialFailure(p.address)
+			p.logger.Warnw("warm pool dial failed", "backend_addr", p.address, "err", err)
+
+			p.dialMu.Lock()
+			p.nextDialAt = time.Now().Add(p.dialBackoff.next())
+			p.dialMu.Unlock()
+			continue
+		}
+
+		if !p.recycle(conn) {
+			conn.underlying.Close()
+		}
 	}
+}
+
+// IdleConns returns the number of dialed-ahead connections currently sitting
+// in the warm pool.
+func (p *pg) IdleConns() int {
+	p.warmMu.Lock()
+	defer p.warmMu.Unlock()
+	return len(p.warm)
+}
 
+// InUseConns returns the number of connections handed out by Connect that
+// haven't yet been closed.
+func (p *pg) InUseConns() int {
+	p.inflightMu.Lock()
+	defer p.inflightMu.Unlock()
+	return len(p.inflight)
+}
+
+// DialErrors returns the running count of failed dial attempts, whether from
+// Connect or the warm pool filler.
+func (p *pg) DialErrors() int64 {
+	return atomic.LoadInt64(&p.dialErrors)
+}
+
+func (p *pg) Connect(t time.Duration) (conn *Conn, err error) {
+	p.dialMu.Lock()
+	if backingOff := time.Now().Before(p.nextDialAt); backingOff {
+		p.dialMu.Unlock()
+		return nil, ErrBackingOff
+	}
+	p.dialMu.Unlock()
+
+	conn = p.takeWarm()
+	if conn == nil {
+		conn, err = p.dialConn(t)
+		if err != nil {
+			atomic.AddInt64(&p.dialErrors, 1)
+			p.metrics.DialFailure(p.address)
+
+			p.dialMu.Lock()
+			p.nextDialAt = time.Now().Add(p.dialBackoff.next())
+			p.dialMu.Unlock()
+
+			p.Fail()
+			return nil, err
+		}
+
+		p.dialMu.Lock()
+		p.dialBackoff.reset()
+		p.nextDialAt = time.Time{}
+		p.dialMu.Unlock()
+	}
+
+	p.inflightMu.Lock()
 	p.inflight[conn] = true
+	p.inflightMu.Unlock()
+
 	closeHandler := func() {
+		p.inflightMu.Lock()
 		delete(p.inflight, conn)
+		p.inflightMu.Unlock()
+
+		if !conn.consumed && p.recycle(conn) {
+			conn.recycled = true
+		}
 	}
 	conn.RegisterCloseHandler(closeHandler)
 
@@ -77,7 +360,42 @@ func (p *pg) Connect(t time.Duration) (conn *Conn, err error) {
 }
 
 func (p *pg) Fail() {
+	p.metrics.BackendFailed(p.address)
+
+	// closeHandler above takes inflightMu itself, so snapshot the keys
+	// before calling Close() rather than ranging under the lock.
+	p.inflightMu.Lock()
+	inflight := make([]*Conn, 0, len(p.inflight))
 	for k := range p.inflight {
+		inflight = append(inflight, k)
+	}
+	p.inflightMu.Unlock()
+
+	for _, k := range inflight {
 		k.Close()
 	}
+
+	// Drop every dialed-ahead connection too; they're unauthenticated
+	// sockets to a backend we've just given up on, not worth keeping warm.
+	p.warmMu.Lock()
+	warm := p.warm
+	p.warm = nil
+	p.warmMu.Unlock()
+
+	for _, conn := range warm {
+		conn.underlying.Close()
+	}
+
+	// A demoted primary must drop its in-flight write connections
+	// immediately; tear the listener down too, rather than let it keep
+	// delivering notifications for a backend we've already given up on.
+	// ensureListener re-establishes it once Ping succeeds again.
+	p.listenerMu.Lock()
+	l := p.listener
+	p.listener = nil
+	p.listenerMu.Unlock()
+
+	if l != nil {
+		l.Close()
+	}
 }
@@ -0,0 +1,197 @@
+// Package scram implements the client side of the SCRAM-SHA-256 and
+// SCRAM-SHA-256-PLUS authentication exchange (RFC 5802, RFC 7677), as
+// required by PostgreSQL servers configured with
+// password_encryption = scram-sha-256.
+package scram
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// Mechanism names as advertised by an AuthenticationSASL request.
+const (
+	SHA256     = "SCRAM-SHA-256"
+	SHA256Plus = "SCRAM-SHA-256-PLUS"
+)
+
+// ErrProtocol means the server sent a SCRAM message that doesn't parse, or
+// is missing an attribute this client requires.
+var ErrProtocol = errors.New("scram: protocol violation")
+
+// ErrServerSignature means the server's final signature didn't match what
+// this client computed; the server either doesn't know the password or
+// something is tampering with the connection.
+var ErrServerSignature = errors.New("scram: server signature mismatch")
+
+// Client drives one SCRAM-SHA-256 exchange on behalf of a username/password
+// pair. A Client is used once, in order:
+//
+//	c := scram.NewClient(user, password)
+//	data := c.FirstMessage()                       // -> AuthenticationSASL response
+//	final, err := c.FinalMessage(serverFirst)       // <- AuthenticationSASLContinue payload
+//	                                                // -> SASLResponse with final
+//	err = c.VerifyServerFinalMessage(serverFinal)   // <- AuthenticationSASLFinal payload
+type Client struct {
+	username string
+	password string
+	nonce    string
+
+	gs2Header string // "n,," (SCRAM-SHA-256) or "p=tls-server-end-point,," (-PLUS)
+	cbindData []byte // channel-binding data, appended after gs2Header for -PLUS
+
+	clientFirstBare string
+	saltedPassword  []byte
+	authMessage     string
+}
+
+// NewClient returns a Client that authenticates without channel binding,
+// i.e. plain SCRAM-SHA-256.
+func NewClient(username, password string) *Client {
+	return &Client{
+		username:  username,
+		password:  password,
+		nonce:     newNonce(),
+		gs2Header: "n,,",
+	}
+}
+
+// NewClientWithChannelBinding returns a Client that binds the exchange to
+// cbindData via SCRAM-SHA-256-PLUS. cbindData is normally the
+// tls-server-end-point channel binding (the TLS certificate's hash) of the
+// connection the exchange runs over.
+func NewClientWithChannelBinding(username, password string, cbindData []byte) *Client {
+	c := NewClient(username, password)
+	c.gs2Header = "p=tls-server-end-point,,"
+	c.cbindData = cbindData
+	return c
+}
+
+// Mechanism returns the SASL mechanism name this client negotiates.
+func (c *Client) Mechanism() string {
+	if c.cbindData != nil {
+		return SHA256Plus
+	}
+	return SHA256
+}
+
+// FirstMessage builds the client-first-message to send as the
+// AuthenticationSASL response's Data.
+func (c *Client) FirstMessage() []byte {
+	c.clientFirstBare = fmt.Sprintf("n=%s,r=%s", escapeUsername(c.username), c.nonce)
+	return []byte(c.gs2Header + c.clientFirstBare)
+}
+
+// FinalMessage consumes the server-first-message (the
+// AuthenticationSASLContinue payload) and returns the client-final-message
+// to send back as a SASLResponse.
+func (c *Client) FinalMessage(serverFirst []byte) ([]byte, error) {
+	attrs, err := parseAttrs(string(serverFirst))
+	if err != nil {
+		return nil, err
+	}
+
+	serverNonce, ok := attrs["r"]
+	if !ok || !strings.HasPrefix(serverNonce, c.nonce) {
+		return nil, ErrProtocol
+	}
+
+	salt, err := base64.StdEncoding.DecodeString(attrs["s"])
+	if err != nil {
+		return nil, ErrProtocol
+	}
+
+	iterations, err := strconv.Atoi(attrs["i"])
+	if err != nil || iterations <= 0 {
+		return nil, ErrProtocol
+	}
+
+	c.saltedPassword = pbkdf2.Key([]byte(c.password), salt, iterations, sha256.Size, sha256.New)
+
+	cbind := base64.StdEncoding.EncodeToString(append([]byte(c.gs2Header), c.cbindData...))
+	clientFinalWithoutProof := fmt.Sprintf("c=%s,r=%s", cbind, serverNonce)
+
+	c.authMessage = strings.Join([]string{c.clientFirstBare, string(serverFirst), clientFinalWithoutProof}, ",")
+
+	clientKey := hmacSHA256(c.saltedPassword, []byte("Client Key"))
+	storedKey := sha256.Sum256(clientKey)
+	clientSignature := hmacSHA256(storedKey[:], []byte(c.authMessage))
+
+	clientProof := make([]byte, len(clientKey))
+	for i := range clientKey {
+		clientProof[i] = clientKey[i] ^ clientSignature[i]
+	}
+
+	final := fmt.Sprintf("%s,p=%s", clientFinalWithoutProof, base64.StdEncoding.EncodeToString(clientProof))
+	return []byte(final), nil
+}
+
+// VerifyServerFinalMessage checks the server-final-message's signature (the
+// AuthenticationSASLFinal payload) against what this client computed, so
+// that a man-in-the-middle can't forge a successful authentication.
+func (c *Client) VerifyServerFinalMessage(serverFinal []byte) error {
+	attrs, err := parseAttrs(string(serverFinal))
+	if err != nil {
+		return err
+	}
+
+	gotSignature, err := base64.StdEncoding.DecodeString(attrs["v"])
+	if err != nil {
+		return ErrProtocol
+	}
+
+	serverKey := hmacSHA256(c.saltedPassword, []byte("Server Key"))
+	wantSignature := hmacSHA256(serverKey, []byte(c.authMessage))
+
+	if subtle.ConstantTimeCompare(gotSignature, wantSignature) != 1 {
+		return ErrServerSignature
+	}
+
+	return nil
+}
+
+func hmacSHA256(key, data []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+// parseAttrs splits a comma-separated list of "k=v" SCRAM attributes.
+func parseAttrs(s string) (map[string]string, error) {
+	attrs := make(map[string]string)
+	for _, part := range strings.Split(s, ",") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return nil, ErrProtocol
+		}
+		attrs[kv[0]] = kv[1]
+	}
+
+	return attrs, nil
+}
+
+// escapeUsername escapes ',' and '=' per RFC 5802 section 5.1.
+func escapeUsername(u string) string {
+	u = strings.ReplaceAll(u, "=", "=3D")
+	u = strings.ReplaceAll(u, ",", "=2C")
+	return u
+}
+
+// newNonce returns a random, printable client nonce.
+func newNonce() string {
+	buf := make([]byte, 18)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+
+	return base64.RawStdEncoding.EncodeToString(buf)
+}
@@ -0,0 +1,139 @@
+package scram
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// fakeServer plays the server side of RFC 5802 just well enough to exercise
+// Client against known-good values, without requiring a real Postgres.
+type fakeServer struct {
+	username   string
+	password   string
+	salt       []byte
+	iterations int
+
+	nonce       string
+	authMessage string
+}
+
+func newFakeServer(username, password string, salt []byte, iterations int) *fakeServer {
+	return &fakeServer{username: username, password: password, salt: salt, iterations: iterations}
+}
+
+func (s *fakeServer) firstMessage(clientFirstBare string) string {
+	s.nonce = attr(clientFirstBare, "r") + "servernonce"
+
+	first := fmt.Sprintf("r=%s,s=%s,i=%d", s.nonce, base64.StdEncoding.EncodeToString(s.salt), s.iterations)
+	s.authMessage = strings.Join([]string{clientFirstBare, first}, ",")
+
+	return first
+}
+
+func (s *fakeServer) verifyAndFinalMessage(clientFinal string) (string, bool) {
+	s.authMessage = strings.Join([]string{s.authMessage, clientFinalWithoutProof(clientFinal)}, ",")
+
+	saltedPassword := pbkdf2.Key([]byte(s.password), s.salt, s.iterations, sha256.Size, sha256.New)
+	clientKey := hmacSHA256(saltedPassword, []byte("Client Key"))
+	storedKey := sha256.Sum256(clientKey)
+	clientSignature := hmacSHA256(storedKey[:], []byte(s.authMessage))
+
+	gotProof, err := base64.StdEncoding.DecodeString(attr(clientFinal, "p"))
+	if err != nil {
+		return "", false
+	}
+
+	wantKey := make([]byte, len(clientKey))
+	for i := range clientSignature {
+		wantKey[i] = gotProof[i] ^ clientSignature[i]
+	}
+	if sha256.Sum256(wantKey) != storedKey {
+		return "", false
+	}
+
+	serverKey := hmacSHA256(saltedPassword, []byte("Server Key"))
+	serverSignature := hmacSHA256(serverKey, []byte(s.authMessage))
+
+	return fmt.Sprintf("v=%s", base64.StdEncoding.EncodeToString(serverSignature)), true
+}
+
+func attr(s, key string) string {
+	for _, part := range strings.Split(s, ",") {
+		if strings.HasPrefix(part, key+"=") {
+			return part[len(key)+1:]
+		}
+	}
+	return ""
+}
+
+func clientFinalWithoutProof(clientFinal string) string {
+	i := strings.LastIndex(clientFinal, ",p=")
+	return clientFinal[:i]
+}
+
+func TestClientSCRAMExchange(t *testing.T) {
+	salt := []byte("deterministic-salt")
+	server := newFakeServer("alice", "s3kr1t", salt, 4096)
+
+	client := NewClient("alice", "s3kr1t")
+	if client.Mechanism() != SHA256 {
+		t.Fatalf("expected mechanism %s, got %s", SHA256, client.Mechanism())
+	}
+
+	clientFirst := client.FirstMessage()
+	gs2Header := string(clientFirst[:3])
+	if gs2Header != "n,," {
+		t.Fatalf("expected gs2-header 'n,,', got %q", gs2Header)
+	}
+
+	serverFirst := server.firstMessage(string(clientFirst[3:]))
+
+	clientFinal, err := client.FinalMessage([]byte(serverFirst))
+	if err != nil {
+		t.Fatalf("FinalMessage returned an error: %v", err)
+	}
+
+	serverFinal, ok := server.verifyAndFinalMessage(string(clientFinal))
+	if !ok {
+		t.Fatalf("server rejected the client's proof")
+	}
+
+	if err := client.VerifyServerFinalMessage([]byte(serverFinal)); err != nil {
+		t.Fatalf("VerifyServerFinalMessage returned an error: %v", err)
+	}
+}
+
+func TestClientRejectsForgedServerSignature(t *testing.T) {
+	salt := []byte("deterministic-salt")
+	server := newFakeServer("alice", "s3kr1t", salt, 4096)
+
+	client := NewClient("alice", "s3kr1t")
+	clientFirst := client.FirstMessage()
+	serverFirst := server.firstMessage(string(clientFirst[3:]))
+
+	clientFinal, err := client.FinalMessage([]byte(serverFirst))
+	if err != nil {
+		t.Fatalf("FinalMessage returned an error: %v", err)
+	}
+
+	if _, ok := server.verifyAndFinalMessage(string(clientFinal)); !ok {
+		t.Fatalf("server rejected the client's proof")
+	}
+
+	forged := fmt.Sprintf("v=%s", base64.StdEncoding.EncodeToString([]byte("not-the-real-signature!")))
+	if err := client.VerifyServerFinalMessage([]byte(forged)); err != ErrServerSignature {
+		t.Fatalf("expected ErrServerSignature, got %v", err)
+	}
+}
+
+func hmacSHA256(key, data []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"hash"
+	"io"
+	"net"
+	"os"
+)
+
+// TLSConfig holds the independent TLS policies for the arbiter's two legs:
+// Client governs the listener side (nil means terminate nothing, answer
+// SSLRequest with 'N'), Backend governs the connections the arbiter opens to
+// Postgres backends (nil means speak plaintext to backends).
+type TLSConfig struct {
+	Client  *tls.Config
+	Backend *tls.Config
+}
+
+// buildTLSConfig turns the TLS section of c into a TLSConfig, or returns an
+// error if a certificate or CA file can't be loaded.
+func buildTLSConfig(c *Config) (*TLSConfig, error) {
+	tc := &TLSConfig{}
+
+	if c.TLS.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.TLS.CertFile, c.TLS.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading TLS.CertFile/TLS.KeyFile: %w", err)
+		}
+
+		clientCfg := &tls.Config{Certificates: []tls.Certificate{cert}}
+
+		if c.TLS.ClientCAFile != "" {
+			pool, err := loadCertPool(c.TLS.ClientCAFile)
+			if err != nil {
+				return nil, fmt.Errorf("loading TLS.ClientCAFile: %w", err)
+			}
+			clientCfg.ClientCAs = pool
+			clientCfg.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+
+		tc.Client = clientCfg
+	}
+
+	switch c.TLS.BackendMode {
+	case "", "disable":
+		// tc.Backend stays nil; backends are dialed in plaintext.
+
+	case "require":
+		tc.Backend = &tls.Config{InsecureSkipVerify: true}
+
+	case "verify-full":
+		pool, err := loadCertPool(c.TLS.BackendCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading TLS.BackendCAFile: %w", err)
+		}
+		tc.Backend = &tls.Config{RootCAs: pool}
+	}
+
+	return tc, nil
+}
+
+// loadCertPool reads a PEM-encoded file of one or more certificates into a
+// new x509.CertPool.
+func loadCertPool(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("%s contains no usable certificates", path)
+	}
+
+	return pool, nil
+}
+
+// negotiateBackendTLS performs the client side of the SSLRequest dance
+// against a freshly-dialed backend connection - write SSLRequest, expect a
+// single 'S' byte back - then upgrades conn with a TLS handshake per cfg.
+//
+// cfg is shared across every backend the arbiter dials, so it carries no
+// ServerName of its own; negotiateBackendTLS clones it per connection and
+// fills ServerName in from conn's actual remote host, which verify-full
+// needs to validate the presented certificate against.
+func negotiateBackendTLS(conn net.Conn, cfg *tls.Config) (net.Conn, error) {
+	if err := (&SSLRequest{}).EncodeTo(conn); err != nil {
+		return nil, err
+	}
+
+	var reply [1]byte
+	if _, err := conn.Read(reply[:]); err != nil {
+		return nil, err
+	}
+
+	if reply[0] != 'S' {
+		return nil, fmt.Errorf("backend refused TLS: got %q, want 'S'", reply[0])
+	}
+
+	connCfg := cfg.Clone()
+	if connCfg.ServerName == "" && !connCfg.InsecureSkipVerify {
+		host, _, err := net.SplitHostPort(conn.RemoteAddr().String())
+		if err != nil {
+			return nil, fmt.Errorf("determining backend TLS ServerName: %w", err)
+		}
+		connCfg.ServerName = host
+	}
+
+	tlsConn := tls.Client(conn, connCfg)
+	if err := tlsConn.Handshake(); err != nil {
+		return nil, err
+	}
+
+	return tlsConn, nil
+}
+
+// tlsChannelBindingHash computes the tls-server-end-point channel binding
+// (RFC 5929 section 4.1) for conn: a hash of the peer's certificate, using
+// the same algorithm the certificate was signed with, or SHA-256 if that
+// algorithm is MD5 or SHA-1 (per RFC 5929, weak signature hashes are never
+// reused for the binding). It returns nil if conn isn't TLS-terminated or
+// the peer presented no certificate, so callers can treat that as "channel
+// binding unavailable" rather than an error.
+func tlsChannelBindingHash(conn io.ReadWriter) []byte {
+	tlsConn, ok := conn.(*tls.Conn)
+	if !ok {
+		return nil
+	}
+
+	certs := tlsConn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return nil
+	}
+
+	var h hash.Hash
+	switch certs[0].SignatureAlgorithm {
+	case x509.MD5WithRSA, x509.DSAWithSHA1, x509.SHA1WithRSA, x509.ECDSAWithSHA1:
+		h = sha256.New()
+	case x509.SHA384WithRSA, x509.ECDSAWithSHA384, x509.SHA384WithRSAPSS:
+		h = sha512.New384()
+	case x509.SHA512WithRSA, x509.ECDSAWithSHA512, x509.SHA512WithRSAPSS:
+		h = sha512.New()
+	default:
+		h = sha256.New()
+	}
+
+	h.Write(certs[0].Raw)
+	return h.Sum(nil)
+}